@@ -0,0 +1,788 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	raw "google.golang.org/api/storage/v1"
+	htransport "google.golang.org/api/transport/http"
+)
+
+type GcsClient struct {
+	h http.Client
+	// endpoint, when set, replaces storage.googleapis.com; this is what
+	// lets tests point the client at a local fake GCS server.
+	endpoint string
+}
+
+func NewGcsClient(ctx context.Context) (c *GcsClient, err error) {
+	creds, err := google.FindDefaultCredentials(ctx, storage.ScopeFullControl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the credentials: %w", err)
+	}
+
+	ts := oauth2.ReuseTokenSourceWithExpiry(nil, creds.TokenSource, time.Minute)
+	tr, err := htransport.NewTransport(ctx, http.DefaultTransport,
+		option.WithTokenSource(ts),
+		option.WithTelemetryDisabled(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the transport: %w", err)
+	}
+
+	c = &GcsClient{
+		h: http.Client{Transport: tr},
+	}
+	return c, nil
+}
+
+// GcsClientOption configures NewGcsClientWithOptions.
+type GcsClientOption func(*gcsClientConfig)
+
+type gcsClientConfig struct {
+	endpoint   string
+	httpClient *http.Client
+	noAuth     bool
+}
+
+// WithEndpoint redirects all requests to endpoint instead of
+// storage.googleapis.com, for pointing the client at a local fake GCS
+// server.
+func WithEndpoint(endpoint string) GcsClientOption {
+	return func(cfg *gcsClientConfig) { cfg.endpoint = strings.TrimRight(endpoint, "/") }
+}
+
+// WithHTTPClient overrides the http.Client the GcsClient issues requests
+// with, instead of one wrapping Application Default Credentials.
+func WithHTTPClient(h *http.Client) GcsClientOption {
+	return func(cfg *gcsClientConfig) { cfg.httpClient = h }
+}
+
+// WithoutAuth skips loading Application Default Credentials entirely, for
+// talking to a fake GCS server that doesn't check authentication.
+func WithoutAuth() GcsClientOption {
+	return func(cfg *gcsClientConfig) { cfg.noAuth = true }
+}
+
+// NewGcsClientWithOptions is NewGcsClient with room to override the
+// endpoint, transport and authentication, primarily so tests can run
+// against a local fake GCS server instead of the real one.
+func NewGcsClientWithOptions(ctx context.Context, opts ...GcsClientOption) (c *GcsClient, err error) {
+	var cfg gcsClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.noAuth {
+		c = &GcsClient{endpoint: cfg.endpoint}
+		if cfg.httpClient != nil {
+			c.h = *cfg.httpClient
+		}
+		return c, nil
+	}
+
+	if c, err = NewGcsClient(ctx); err != nil {
+		return nil, err
+	}
+	c.endpoint = cfg.endpoint
+	if cfg.httpClient != nil {
+		c.h = *cfg.httpClient
+	}
+
+	return c, nil
+}
+
+// UploadObject streams data to bucket/name in a single PUT. size is the
+// number of bytes data will yield, or -1 if unknown, in which case the
+// request is sent with chunked transfer encoding.
+func (c *GcsClient) UploadObject(ctx context.Context, bucket, name string, data io.Reader, size int64) (err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, c.objectUrl(bucket, name),
+		data)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload failed with status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *GcsClient) NewUploadSession(ctx context.Context, bucket, name string) (uploadUrl string, err error) {
+	args := saveJson(raw.Object{
+		Bucket: bucket,
+		Name:   name,
+	})
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPost, c.newResumableUploadUrl(bucket),
+		bytes.NewReader(args))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return "", nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("starting an upload failed with status %q", resp.Status)
+	}
+
+	uploadUrl = resp.Header.Get("Location")
+	if uploadUrl == "" {
+		return "", fmt.Errorf("no location header in the response")
+	}
+
+	return uploadUrl, nil
+}
+
+// UploadObjectPart streams a single chunk of size bytes from data into the
+// resumable session at uploadUrl, starting at off.
+func (c *GcsClient) UploadObjectPart(ctx context.Context, uploadUrl string, off int64, data io.Reader, size int64, last bool) (err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, uploadUrl,
+		data)
+	if err != nil {
+		return err
+	}
+
+	var contentRange string
+	if last {
+		if size == 0 {
+			contentRange = fmt.Sprintf("bytes */%d", off)
+		} else {
+			begin, end := off, off+size
+			contentRange = fmt.Sprintf("bytes %d-%d/%d", begin, end-1, end)
+		}
+	} else {
+		if size%googleapi.MinUploadChunkSize != 0 {
+			return fmt.Errorf("unaligned chunk, size=%d", size)
+		}
+		if size == 0 {
+			return fmt.Errorf("only the last chunk may be empty")
+		}
+
+		begin, end := off, off+size
+		contentRange = fmt.Sprintf("bytes %d-%d/*", begin, end-1)
+	}
+
+	req.Header.Set("Content-Range", contentRange)
+	req.ContentLength = size
+	req.Header.Set("X-GUploader-No-308", "yes")
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	_, _, err = c.parseOffsetResponse(resp)
+	return err
+}
+
+func (c *GcsClient) GetResumeOffset(ctx context.Context, uploadUrl string) (off int64, complete bool, err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, uploadUrl,
+		http.NoBody)
+	if err != nil {
+		return 0, false, err
+	}
+
+	req.Header.Set("Content-Range", "bytes */*")
+	req.Header.Set("X-GUploader-No-308", "yes")
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	return c.parseOffsetResponse(resp)
+}
+
+func (c *GcsClient) parseOffsetResponse(resp *http.Response) (off int64, complete bool, err error) {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return 0, false, fmt.Errorf("chunk upload failed with status %q", resp.Status)
+	}
+
+	if resp.Header.Get("X-HTTP-Status-Code-Override") != "308" {
+		// An object upload was successfully completed. This response has
+		// no Range: header, but the body is a JSON describing the uploaded
+		// object.
+
+		var obj raw.Object
+		if err = json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+			return 0, false, fmt.Errorf("GCS response is not a valid JSON")
+		}
+		return int64(obj.Size), true, nil
+	}
+
+	r := resp.Header.Get("Range")
+	if r == "" {
+		// This may happen after uploading 0 bytes.
+		return 0, false, nil
+	}
+
+	if _, err = fmt.Sscanf(r, "bytes=0-%d", &off); err != nil {
+		return 0, false, fmt.Errorf("GCS sent a malformed Range: as a reply: %q", r)
+	}
+
+	// Range: specifies the bytes range as [0, x] instead of [0, x).
+	off += 1
+
+	return off, false, nil
+}
+
+func (c *GcsClient) CancelUpload(ctx context.Context, uploadUrl string) (err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodDelete, uploadUrl,
+		http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	// for some reason GCS may reply 499 to this request
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != 499 {
+		return fmt.Errorf("upload failed with status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *GcsClient) objectUrl(bucket, name string) string {
+	if c.endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", c.endpoint, bucket, name)
+	}
+	return fmt.Sprintf("https://%s.storage.googleapis.com/%s", bucket, name)
+}
+
+func (c *GcsClient) newResumableUploadUrl(bucket string) string {
+	if c.endpoint != "" {
+		return fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=resumable", c.endpoint, bucket)
+	}
+	return fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=resumable", bucket)
+}
+
+func (c *GcsClient) composeUrl(bucket, name string) string {
+	if c.endpoint != "" {
+		return fmt.Sprintf("%s/storage/v1/b/%s/o/%s/compose", c.endpoint, bucket, name)
+	}
+	return fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s/compose", bucket, name)
+}
+
+// ParallelOpts configures UploadObjectParallel. Zero values fall back to
+// defaults modeled on the part sizing/fan-out used by mature GCS drivers.
+type ParallelOpts struct {
+	PartSize    int64
+	Concurrency int
+}
+
+const (
+	defaultParallelPartSize    = 16 * 1024 * 1024
+	defaultParallelConcurrency = 50
+)
+
+type uploadedPart struct {
+	index  int
+	name   string
+	crc32c uint32
+}
+
+// UploadObjectParallel splits data into opts.PartSize chunks, uploads them
+// concurrently as temporary objects via opts.Concurrency workers, and
+// composes them into the final object, deleting the temporaries afterwards.
+func (c *GcsClient) UploadObjectParallel(ctx context.Context, bucket, name string, data io.Reader, opts ParallelOpts) (err error) {
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = defaultParallelPartSize
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultParallelConcurrency
+	}
+
+	var (
+		mu    sync.Mutex
+		parts []uploadedPart
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, concurrency)
+		errCh = make(chan error, 1)
+	)
+
+	reportErr := func(e error) {
+		select {
+		case errCh <- e:
+		default:
+		}
+	}
+
+	buf := make([]byte, partSize)
+	index := 0
+	for {
+		n, rerr := io.ReadFull(data, buf)
+		if n > 0 {
+			index++
+			chunk := append([]byte(nil), buf[:n]...)
+			partName := fmt.Sprintf("%s.part%d.%d", name, index, time.Now().UnixNano())
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(idx int, partName string, chunk []byte) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				sum := crc32.Checksum(chunk, crc32.MakeTable(crc32.Castagnoli))
+				if uerr := c.uploadPart(ctx, bucket, partName, chunk, sum); uerr != nil {
+					reportErr(fmt.Errorf("part %d: %w", idx, uerr))
+					return
+				}
+
+				mu.Lock()
+				parts = append(parts, uploadedPart{index: idx, name: partName, crc32c: sum})
+				mu.Unlock()
+			}(index, partName, chunk)
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			wg.Wait()
+			c.cleanupParts(ctx, bucket, parts)
+			return rerr
+		}
+	}
+	wg.Wait()
+
+	select {
+	case err = <-errCh:
+		c.cleanupParts(ctx, bucket, parts)
+		return err
+	default:
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].index < parts[j].index })
+
+	intermediates, err := c.composeParts(ctx, bucket, name, parts)
+	c.cleanupParts(ctx, bucket, parts)
+	c.cleanupNames(ctx, bucket, intermediates)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *GcsClient) uploadPart(ctx context.Context, bucket, name string, data []byte, crc32c uint32) (err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, c.objectUrl(bucket, name),
+		bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("part upload failed with status %q", resp.Status)
+	}
+
+	want := base64.StdEncoding.EncodeToString(crc32cBytes(crc32c))
+	if got := crc32cFromHashHeader(resp.Header.Get("x-goog-hash")); got != "" && got != want {
+		return fmt.Errorf("crc32c mismatch for part %q: got %s, want %s", name, got, want)
+	}
+
+	return nil
+}
+
+// maxComposeSources is the limit GCS's compose API places on the number of
+// source objects a single compose request may reference.
+const maxComposeSources = 32
+
+// composeParts stitches parts into the final object. When there are more
+// than maxComposeSources of them, it composes them tournament-style: batches
+// of up to maxComposeSources are composed into intermediate objects, which
+// are then composed again (recursively) until a single compose call can
+// produce the final object. The names of any intermediate objects created
+// along the way are returned so the caller can clean them up too.
+func (c *GcsClient) composeParts(ctx context.Context, bucket, name string, parts []uploadedPart) (intermediates []string, err error) {
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		names[i] = p.name
+	}
+
+	for tier := 0; len(names) > maxComposeSources; tier++ {
+		var next []string
+		for i := 0; i < len(names); i += maxComposeSources {
+			batch := names[i:min(i+maxComposeSources, len(names))]
+
+			tmpName := fmt.Sprintf("%s.compose%d.%d", name, tier, i/maxComposeSources)
+			if err = c.composeObjects(ctx, bucket, tmpName, batch); err != nil {
+				return intermediates, err
+			}
+
+			intermediates = append(intermediates, tmpName)
+			next = append(next, tmpName)
+		}
+		names = next
+	}
+
+	if err = c.composeObjects(ctx, bucket, name, names); err != nil {
+		return intermediates, err
+	}
+
+	return intermediates, nil
+}
+
+func (c *GcsClient) composeObjects(ctx context.Context, bucket, destName string, sourceNames []string) (err error) {
+	sources := make([]*raw.ComposeRequestSourceObjects, len(sourceNames))
+	for i, n := range sourceNames {
+		sources[i] = &raw.ComposeRequestSourceObjects{Name: n}
+	}
+
+	body := saveJson(raw.ComposeRequest{
+		Destination:   &raw.Object{Bucket: bucket, Name: destName},
+		SourceObjects: sources,
+	})
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPost, c.composeUrl(bucket, destName),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("compose failed with status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *GcsClient) deleteObject(ctx context.Context, bucket, name string) (err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodDelete, c.objectUrl(bucket, name),
+		nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("delete failed with status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *GcsClient) cleanupParts(ctx context.Context, bucket string, parts []uploadedPart) {
+	// best-effort: a leftover temporary part is harmless, but a failed
+	// upload must not leave the caller blocked on cleanup errors
+	for _, p := range parts {
+		_ = c.deleteObject(ctx, bucket, p.name)
+	}
+}
+
+// cleanupNames is cleanupParts for the intermediate objects created by a
+// tiered composeParts, which aren't tracked as uploadedPart values.
+func (c *GcsClient) cleanupNames(ctx context.Context, bucket string, names []string) {
+	for _, n := range names {
+		_ = c.deleteObject(ctx, bucket, n)
+	}
+}
+
+func crc32cBytes(sum uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, sum)
+	return b
+}
+
+func crc32cFromHashHeader(h string) string {
+	for _, field := range strings.Split(h, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok && k == "crc32c" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ResumableOpts configures UploadObjectResumable.
+type ResumableOpts struct {
+	// OnProgress, if set, is called after every chunk that is accepted by
+	// GCS, including ones sent again during retries.
+	OnProgress func(uploaded, total int64)
+}
+
+const (
+	resumableRetryBase   = time.Second
+	resumableRetryFactor = 2
+	resumableRetryCap    = 32 * time.Second
+	resumableMaxAttempts = 5
+)
+
+// UploadObjectResumable owns a resumable session end-to-end: it starts the
+// session, uploads chunkSize-sized chunks read from r, and on a transient
+// failure re-syncs with GCS via GetResumeOffset before retrying with
+// truncated exponential backoff.
+func (c *GcsClient) UploadObjectResumable(ctx context.Context, bucket, name string, r io.ReaderAt, size int64, chunkSize int, opts ResumableOpts) (err error) {
+	uploadUrl, err := c.NewUploadSession(ctx, bucket, name)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	off := int64(0)
+
+	for off < size {
+		n := int64(chunkSize)
+		last := false
+		if off+n >= size {
+			n = size - off
+			last = true
+		}
+
+		read, rerr := r.ReadAt(buf[:n], off)
+		if rerr != nil && rerr != io.EOF {
+			return rerr
+		}
+		if int64(read) != n {
+			return fmt.Errorf("short read at offset %d: got %d bytes, want %d", off, read, n)
+		}
+
+		off, err = c.uploadChunkWithRetry(ctx, uploadUrl, off, buf[:n], last)
+		if err != nil {
+			return err
+		}
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(off, size)
+		}
+	}
+
+	return nil
+}
+
+func (c *GcsClient) uploadChunkWithRetry(ctx context.Context, uploadUrl string, off int64, data []byte, last bool) (newOff int64, err error) {
+	chunkEnd := off + int64(len(data))
+	backoff := resumableRetryBase
+
+	for attempt := 1; attempt <= resumableMaxAttempts; attempt++ {
+		status, respOff, complete, uerr := c.uploadChunkOnce(ctx, uploadUrl, off, data, last)
+
+		if uerr == nil && complete {
+			return chunkEnd, nil
+		}
+		if uerr == nil && respOff == chunkEnd {
+			return respOff, nil
+		}
+
+		// uerr == nil here means a 308 landed with a Range we didn't expect,
+		// including the "no Range header after 0 bytes" case from
+		// parseOffsetResponse; that's recoverable the same way a transient
+		// network/5xx/429 failure is, by resyncing with GetResumeOffset.
+		// status 0 means no response arrived at all, i.e. a network error.
+		retriable := uerr == nil || (status == 0 && ctx.Err() == nil) || isRetriableStatus(status)
+		if !retriable || attempt == resumableMaxAttempts {
+			if uerr != nil {
+				return 0, uerr
+			}
+			return 0, fmt.Errorf("resumable upload offset %d outside of the expected [%d, %d)", respOff, off, chunkEnd)
+		}
+
+		time.Sleep(jitter(backoff))
+		backoff *= resumableRetryFactor
+		if backoff > resumableRetryCap {
+			backoff = resumableRetryCap
+		}
+
+		recovered, roComplete, rerr := c.GetResumeOffset(ctx, uploadUrl)
+		if rerr != nil {
+			continue
+		}
+		if roComplete {
+			return chunkEnd, nil
+		}
+		if recovered < off || recovered > chunkEnd {
+			return 0, fmt.Errorf("resumable upload offset %d outside of the chunk [%d, %d)", recovered, off, chunkEnd)
+		}
+		data = data[recovered-off:]
+		off = recovered
+	}
+
+	return 0, fmt.Errorf("giving up on chunk [%d, %d) after %d attempts", off, chunkEnd, resumableMaxAttempts)
+}
+
+func (c *GcsClient) uploadChunkOnce(ctx context.Context, uploadUrl string, off int64, data []byte, last bool) (status int, newOff int64, complete bool, err error) {
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPut, uploadUrl,
+		bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	var contentRange string
+	if last {
+		if len(data) == 0 {
+			contentRange = fmt.Sprintf("bytes */%d", off)
+		} else {
+			begin, end := off, off+int64(len(data))
+			contentRange = fmt.Sprintf("bytes %d-%d/%d", begin, end-1, end)
+		}
+	} else {
+		begin, end := off, off+int64(len(data))
+		contentRange = fmt.Sprintf("bytes %d-%d/*", begin, end-1)
+	}
+
+	req.Header.Set("Content-Range", contentRange)
+	req.ContentLength = int64(len(data))
+	req.Header.Set("X-GUploader-No-308", "yes")
+
+	resp, err := c.h.Do(req)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer resp.Body.Close()
+
+	newOff, complete, err = c.parseOffsetResponse(resp)
+	return resp.StatusCode, newOff, complete, err
+}
+
+func isRetriableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2+1)))
+}
+
+const writerChunkSize = 8 * 1024 * 1024
+
+var writerBufPool = sync.Pool{
+	New: func() any { return make([]byte, writerChunkSize) },
+}
+
+// GcsWriter is an io.WriteCloser that streams writes to GCS as resumable
+// chunks, buffering at most one chunk at a time via writerBufPool instead
+// of holding the whole object in memory.
+type GcsWriter struct {
+	c         *GcsClient
+	ctx       context.Context
+	uploadUrl string
+
+	buf    []byte
+	filled int
+	off    int64
+	closed bool
+}
+
+// NewWriter starts a resumable session for bucket/name and returns a
+// GcsWriter that uploads it in writerChunkSize pieces as callers write to
+// it, for objects whose size isn't known upfront.
+func (c *GcsClient) NewWriter(ctx context.Context, bucket, name string) (w *GcsWriter, err error) {
+	uploadUrl, err := c.NewUploadSession(ctx, bucket, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GcsWriter{
+		c:         c,
+		ctx:       ctx,
+		uploadUrl: uploadUrl,
+		buf:       writerBufPool.Get().([]byte),
+	}, nil
+}
+
+func (w *GcsWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, fmt.Errorf("write to a closed GcsWriter")
+	}
+
+	for len(p) > 0 {
+		room := copy(w.buf[w.filled:], p)
+		w.filled += room
+		p = p[room:]
+		n += room
+
+		if w.filled == len(w.buf) {
+			if err = w.flush(false); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (w *GcsWriter) flush(last bool) (err error) {
+	if err = w.c.UploadObjectPart(w.ctx, w.uploadUrl, w.off, bytes.NewReader(w.buf[:w.filled]), int64(w.filled), last); err != nil {
+		return err
+	}
+
+	w.off += int64(w.filled)
+	w.filled = 0
+	return nil
+}
+
+// Close flushes the final, possibly undersized and possibly empty, chunk
+// and releases the write buffer back to writerBufPool.
+func (w *GcsWriter) Close() (err error) {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err = w.flush(true)
+
+	writerBufPool.Put(w.buf)
+	w.buf = nil
+
+	return err
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"google.golang.org/api/googleapi"
+)
+
+const testBucket = "test-bucket"
+
+func newTestGcsClient(t *testing.T, server *fakestorage.Server) *GcsClient {
+	t.Helper()
+
+	c, err := NewGcsClientWithOptions(context.Background(),
+		WithEndpoint(server.URL()),
+		WithHTTPClient(server.HTTPClient()),
+		WithoutAuth())
+	if err != nil {
+		t.Fatalf("NewGcsClientWithOptions() failed: %v", err)
+	}
+	return c
+}
+
+// freePort grabs an ephemeral port and releases it immediately, so it can
+// be handed to fakestorage.Options up front: PublicHost has to be known
+// before the server starts, since it's what fake-gcs-server matches
+// incoming requests' Host header against.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve a port: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func newTestServer(t *testing.T) *fakestorage.Server {
+	t.Helper()
+
+	port := freePort(t)
+	publicHost := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	server, err := fakestorage.NewServerWithOptions(fakestorage.Options{
+		Scheme:     "http",
+		Host:       "127.0.0.1",
+		Port:       uint16(port),
+		PublicHost: publicHost,
+	})
+	if err != nil {
+		t.Fatalf("fakestorage.NewServerWithOptions() failed: %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	server.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: testBucket})
+	return server
+}
+
+func TestUploadObject(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestGcsClient(t, server)
+
+	want := []byte("hello from the benchmark")
+	if err := c.UploadObject(context.Background(), testBucket, "obj", bytes.NewReader(want), int64(len(want))); err != nil {
+		t.Fatalf("UploadObject() failed: %v", err)
+	}
+
+	obj, err := server.GetObject(testBucket, "obj")
+	if err != nil {
+		t.Fatalf("GetObject() failed: %v", err)
+	}
+	if !bytes.Equal(obj.Content, want) {
+		t.Fatalf("uploaded content = %q, want %q", obj.Content, want)
+	}
+}
+
+func TestResumableUploadFlow(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestGcsClient(t, server)
+	ctx := context.Background()
+
+	first := bytes.Repeat([]byte("a"), googleapi.MinUploadChunkSize)
+	second := []byte("tail")
+
+	uploadUrl, err := c.NewUploadSession(ctx, testBucket, "obj")
+	if err != nil {
+		t.Fatalf("NewUploadSession() failed: %v", err)
+	}
+
+	if err = c.UploadObjectPart(ctx, uploadUrl, 0, bytes.NewReader(first), int64(len(first)), false); err != nil {
+		t.Fatalf("UploadObjectPart() (first chunk) failed: %v", err)
+	}
+
+	off, complete, err := c.GetResumeOffset(ctx, uploadUrl)
+	if err != nil {
+		t.Fatalf("GetResumeOffset() after the first chunk failed: %v", err)
+	}
+	if complete {
+		t.Fatalf("GetResumeOffset() reported complete after only one chunk")
+	}
+	if off != int64(len(first)) {
+		t.Fatalf("GetResumeOffset() off = %d, want %d", off, len(first))
+	}
+
+	if err = c.UploadObjectPart(ctx, uploadUrl, off, bytes.NewReader(second), int64(len(second)), true); err != nil {
+		t.Fatalf("UploadObjectPart() (last chunk) failed: %v", err)
+	}
+
+	off, complete, err = c.GetResumeOffset(ctx, uploadUrl)
+	if err != nil {
+		t.Fatalf("GetResumeOffset() after the last chunk failed: %v", err)
+	}
+	if !complete {
+		t.Fatalf("GetResumeOffset() did not report complete after the last chunk")
+	}
+	if want := int64(len(first) + len(second)); off != want {
+		t.Fatalf("GetResumeOffset() off = %d, want %d", off, want)
+	}
+
+	obj, err := server.GetObject(testBucket, "obj")
+	if err != nil {
+		t.Fatalf("GetObject() failed: %v", err)
+	}
+	if want := append(append([]byte(nil), first...), second...); !bytes.Equal(obj.Content, want) {
+		t.Fatalf("uploaded content has length %d, want %d", len(obj.Content), len(want))
+	}
+}
+
+func TestCancelUpload(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestGcsClient(t, server)
+	ctx := context.Background()
+
+	uploadUrl, err := c.NewUploadSession(ctx, testBucket, "obj")
+	if err != nil {
+		t.Fatalf("NewUploadSession() failed: %v", err)
+	}
+
+	// fake-gcs-server doesn't register DELETE on the resumable upload
+	// route, so it can't serve a real cancellation; what we can pin down
+	// here is that CancelUpload builds a well-formed request and surfaces
+	// the server's response as an error instead of swallowing it.
+	if err = c.CancelUpload(ctx, uploadUrl); err == nil {
+		t.Fatalf("CancelUpload() succeeded against a fake server that doesn't support it")
+	}
+}
+
+func TestParseOffsetResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCode   int
+		override     string
+		rangeHeader  string
+		body         string
+		wantOff      int64
+		wantComplete bool
+		wantErr      bool
+	}{
+		{
+			name:         "308 with a Range header",
+			statusCode:   http.StatusOK,
+			override:     "308",
+			rangeHeader:  "bytes=0-1048575",
+			wantOff:      1048576,
+			wantComplete: false,
+		},
+		{
+			name:         "308 with no Range header after 0 bytes",
+			statusCode:   http.StatusOK,
+			override:     "308",
+			rangeHeader:  "",
+			wantOff:      0,
+			wantComplete: false,
+		},
+		{
+			name:         "final chunk completes the object",
+			statusCode:   http.StatusOK,
+			override:     "",
+			body:         `{"size":"1048580"}`,
+			wantOff:      1048580,
+			wantComplete: true,
+		},
+		{
+			name:       "non-2xx status",
+			statusCode: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+		{
+			name:        "malformed Range header",
+			statusCode:  http.StatusOK,
+			override:    "308",
+			rangeHeader: "not-a-range",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+			}
+			if tt.override != "" {
+				resp.Header.Set("X-HTTP-Status-Code-Override", tt.override)
+			}
+			if tt.rangeHeader != "" {
+				resp.Header.Set("Range", tt.rangeHeader)
+			}
+
+			c := &GcsClient{}
+			off, complete, err := c.parseOffsetResponse(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOffsetResponse() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if off != tt.wantOff || complete != tt.wantComplete {
+				t.Fatalf("parseOffsetResponse() = (%d, %t), want (%d, %t)", off, complete, tt.wantOff, tt.wantComplete)
+			}
+		})
+	}
+}
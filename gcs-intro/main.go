@@ -29,15 +29,17 @@ var uploadObj = &cobra.Command{
 }
 
 var uploadObjArgs struct {
-	bucket string
-	size   string
-	repeat int
+	bucket  string
+	size    string
+	repeat  int
+	backend string
 }
 
 func init() {
 	uploadObj.Flags().StringVarP(&uploadObjArgs.bucket, "bucket", "b", "", "destination bucket")
 	uploadObj.Flags().StringVarP(&uploadObjArgs.size, "size", "s", "4KB", "file size")
 	uploadObj.Flags().IntVarP(&uploadObjArgs.repeat, "repeat", "r", 5, "repetitions")
+	uploadObj.Flags().StringVar(&uploadObjArgs.backend, "backend", "gcs", "storage backend: gcs, s3 or azure")
 	rootCmd.AddCommand(uploadObj)
 }
 
@@ -57,7 +59,7 @@ func mainUploadObj(self *cobra.Command, args []string) error {
 	measurements := make([]float64, repetitions)
 
 	for i := 0; i < repetitions; i++ {
-		time, err := runUploadObj(uploadObjArgs.bucket, int(sizeBytes))
+		time, err := runUploadObj(uploadObjArgs.bucket, int(sizeBytes), uploadObjArgs.backend)
 		if err != nil {
 			return err
 		}
@@ -74,18 +76,17 @@ func mainUploadObj(self *cobra.Command, args []string) error {
 	return nil
 }
 
-func runUploadObj(bucket string, size int) (time.Duration, error) {
+func runUploadObj(bucket string, size int, backend string) (time.Duration, error) {
 	ctx := context.Background()
 
-	c, err := NewGcsClient(ctx)
+	store, err := newObjectStore(ctx, backend)
 	if err != nil {
 		return 0, err
 	}
 
 	start := time.Now()
 
-	buf := makeRandBuf(size)
-	if err = c.UploadObject(ctx, bucket, "x", buf); err != nil {
+	if err = store.UploadObject(ctx, bucket, "x", randReader(int64(size)), int64(size)); err != nil {
 		return 0, err
 	}
 
@@ -101,12 +102,14 @@ var uploadMultipartObjArgs struct {
 	bucket    string
 	chunkSize string
 	repeat    int
+	backend   string
 }
 
 func init() {
 	uploadMultipartObj.Flags().StringVarP(&uploadMultipartObjArgs.bucket, "bucket", "b", "", "destination bucket")
 	uploadMultipartObj.Flags().StringVarP(&uploadMultipartObjArgs.chunkSize, "chunk", "c", "256KB", "chunk size")
 	uploadMultipartObj.Flags().IntVarP(&uploadMultipartObjArgs.repeat, "repeat", "r", 5, "repetitions")
+	uploadMultipartObj.Flags().StringVar(&uploadMultipartObjArgs.backend, "backend", "gcs", "storage backend: gcs, s3 or azure")
 	rootCmd.AddCommand(uploadMultipartObj)
 }
 
@@ -127,7 +130,7 @@ func mainUploadMultipartObj(self *cobra.Command, args []string) (err error) {
 	measurements := make([]float64, repetitions)
 
 	for i := 0; i < repetitions; i++ {
-		time, err := runUploadMultipartObj(uploadMultipartObjArgs.bucket, int(chunkSizeBytes))
+		time, err := runUploadMultipartObj(uploadMultipartObjArgs.bucket, int(chunkSizeBytes), uploadMultipartObjArgs.backend)
 		if err != nil {
 			return err
 		}
@@ -144,40 +147,39 @@ func mainUploadMultipartObj(self *cobra.Command, args []string) (err error) {
 	return nil
 }
 
-func runUploadMultipartObj(bucket string, chunkSize int) (time.Duration, error) {
+func runUploadMultipartObj(bucket string, chunkSize int, backend string) (time.Duration, error) {
 	ctx := context.Background()
 
-	c, err := NewGcsClient(ctx)
+	store, err := newObjectStore(ctx, backend)
 	if err != nil {
 		return 0, err
 	}
 
 	start := time.Now()
 
-	uploadUrl, err := c.NewUploadSession(ctx, bucket, "x")
+	uploadUrl, err := store.NewUploadSession(ctx, bucket, "x")
 	if err != nil {
 		return 0, err
 	}
 
-	off, buf := int64(0), makeRandBuf(2*chunkSize)
+	off := int64(0)
 
-	if err = c.UploadObjectPart(ctx, uploadUrl, off, buf[:chunkSize], false); err != nil {
+	if err = store.UploadObjectPart(ctx, uploadUrl, off, randReader(int64(chunkSize)), int64(chunkSize), false); err != nil {
 		return 0, err
 	}
 	off += int64(chunkSize)
-	buf = buf[chunkSize:]
 
-	testOff, testLast, err := c.GetResumeOffset(ctx, uploadUrl)
+	testOff, testLast, err := store.GetResumeOffset(ctx, uploadUrl)
 	if err != nil {
 		return 0, err
 	}
 	fmt.Printf("GetResumeOffset() = %d, %t\n", testOff, testLast)
 
-	if err = c.UploadObjectPart(ctx, uploadUrl, off, buf[:chunkSize], true); err != nil {
+	if err = store.UploadObjectPart(ctx, uploadUrl, off, randReader(int64(chunkSize)), int64(chunkSize), true); err != nil {
 		return 0, err
 	}
 
-	testOff, testLast, err = c.GetResumeOffset(ctx, uploadUrl)
+	testOff, testLast, err = store.GetResumeOffset(ctx, uploadUrl)
 	if err != nil {
 		return 0, err
 	}
@@ -186,6 +188,85 @@ func runUploadMultipartObj(bucket string, chunkSize int) (time.Duration, error)
 	return time.Since(start), nil
 }
 
+var uploadParallelObj = &cobra.Command{
+	Use:  "pobj",
+	RunE: mainUploadParallelObj,
+}
+
+var uploadParallelObjArgs struct {
+	bucket      string
+	size        string
+	chunk       string
+	concurrency int
+	repeat      int
+}
+
+func init() {
+	uploadParallelObj.Flags().StringVarP(&uploadParallelObjArgs.bucket, "bucket", "b", "", "destination bucket")
+	uploadParallelObj.Flags().StringVarP(&uploadParallelObjArgs.size, "size", "s", "64MB", "file size")
+	uploadParallelObj.Flags().StringVarP(&uploadParallelObjArgs.chunk, "chunk", "c", "16MB", "part size")
+	uploadParallelObj.Flags().IntVarP(&uploadParallelObjArgs.concurrency, "concurrency", "j", 50, "concurrent part uploads")
+	uploadParallelObj.Flags().IntVarP(&uploadParallelObjArgs.repeat, "repeat", "r", 5, "repetitions")
+	rootCmd.AddCommand(uploadParallelObj)
+}
+
+func mainUploadParallelObj(self *cobra.Command, args []string) error {
+	if uploadParallelObjArgs.bucket == "" {
+		return errors.New("destination bucket must be specified")
+	}
+
+	size, err := datasize.ParseString(uploadParallelObjArgs.size)
+	if err != nil {
+		return err
+	}
+	chunk, err := datasize.ParseString(uploadParallelObjArgs.chunk)
+	if err != nil {
+		return err
+	}
+
+	sizeBytes := size.Bytes()
+	repetitions := uploadParallelObjArgs.repeat
+
+	measurements := make([]float64, repetitions)
+
+	for i := 0; i < repetitions; i++ {
+		time, err := runUploadParallelObj(uploadParallelObjArgs.bucket, int(sizeBytes), int64(chunk.Bytes()), uploadParallelObjArgs.concurrency)
+		if err != nil {
+			return err
+		}
+
+		bps := float64(sizeBytes) / time.Seconds()
+		measurements[i] = bps
+
+		bps_size := datasize.ByteSize(bps)
+		fmt.Printf("repetition %d\ttime %s\tspeed %s/s\n", i+1, time.String(), bps_size.HumanReadable())
+	}
+
+	printSpeedAndVariance(repetitions, measurements)
+
+	return nil
+}
+
+func runUploadParallelObj(bucket string, size int, chunkSize int64, concurrency int) (time.Duration, error) {
+	ctx := context.Background()
+
+	c, err := NewGcsClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+
+	if err = c.UploadObjectParallel(ctx, bucket, "x", randReader(int64(size)), ParallelOpts{
+		PartSize:    chunkSize,
+		Concurrency: concurrency,
+	}); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
 func printSpeedAndVariance(repetitions int, measurements []float64) {
 	totalSpeed := float64(0)
 	for i := 0; i < repetitions; i++ {
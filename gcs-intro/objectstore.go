@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ObjectStore is the common surface the benchmark drives across cloud
+// providers: a single-shot upload plus a resumable/multipart session made
+// of opaque session tokens (uploadUrl), so obj/mobj can run unmodified
+// against whichever backend is selected. data is streamed rather than
+// buffered; size is the number of bytes data will yield, or -1 if unknown.
+type ObjectStore interface {
+	UploadObject(ctx context.Context, bucket, name string, data io.Reader, size int64) (err error)
+	NewUploadSession(ctx context.Context, bucket, name string) (uploadUrl string, err error)
+	UploadObjectPart(ctx context.Context, uploadUrl string, off int64, data io.Reader, size int64, last bool) (err error)
+	GetResumeOffset(ctx context.Context, uploadUrl string) (off int64, complete bool, err error)
+	CancelUpload(ctx context.Context, uploadUrl string) (err error)
+}
+
+func newObjectStore(ctx context.Context, backend string) (ObjectStore, error) {
+	switch backend {
+	case "", "gcs":
+		return NewGcsClient(ctx)
+	case "s3":
+		return NewS3Client(ctx)
+	case "azure":
+		return NewAzureClient(ctx)
+	default:
+		return nil, fmt.Errorf("unknown backend %q, want one of gcs, s3, azure", backend)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Client implements ObjectStore on top of S3's multipart upload API.
+// The uploadUrl tokens handed back to callers are just the S3 UploadId;
+// the bucket/key/parts bookkeeping they need is kept in sessions.
+type S3Client struct {
+	svc *s3.Client
+
+	mu       sync.Mutex
+	sessions map[string]*s3Session
+}
+
+type s3Session struct {
+	bucket string
+	key    string
+	parts  []types.CompletedPart
+	next   int32
+
+	// size and completed track the outcome of CompleteMultipartUpload,
+	// since ListParts fails with NoSuchUpload once an upload ID has been
+	// completed and GetResumeOffset still needs an answer for it.
+	size      int64
+	completed bool
+}
+
+func NewS3Client(ctx context.Context) (c *S3Client, err error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the AWS config: %w", err)
+	}
+
+	c = &S3Client{
+		svc:      s3.NewFromConfig(cfg),
+		sessions: make(map[string]*s3Session),
+	}
+	return c, nil
+}
+
+func (c *S3Client) UploadObject(ctx context.Context, bucket, name string, data io.Reader, size int64) (err error) {
+	in := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(name),
+		Body:   data,
+	}
+	if size >= 0 {
+		in.ContentLength = aws.Int64(size)
+	}
+
+	_, err = c.svc.PutObject(ctx, in)
+	return err
+}
+
+func (c *S3Client) NewUploadSession(ctx context.Context, bucket, name string) (uploadUrl string, err error) {
+	out, err := c.svc.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	uploadUrl = aws.ToString(out.UploadId)
+
+	c.mu.Lock()
+	c.sessions[uploadUrl] = &s3Session{bucket: bucket, key: name, next: 1}
+	c.mu.Unlock()
+
+	return uploadUrl, nil
+}
+
+func (c *S3Client) UploadObjectPart(ctx context.Context, uploadUrl string, off int64, data io.Reader, size int64, last bool) (err error) {
+	sess, ok := c.session(uploadUrl)
+	if !ok {
+		return fmt.Errorf("unknown upload session %q", uploadUrl)
+	}
+
+	c.mu.Lock()
+	partNumber := sess.next
+	sess.next++
+	c.mu.Unlock()
+
+	out, err := c.svc.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(sess.bucket),
+		Key:           aws.String(sess.key),
+		UploadId:      aws.String(uploadUrl),
+		PartNumber:    aws.Int32(partNumber),
+		Body:          data,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	sess.parts = append(sess.parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(partNumber)})
+	sess.size += size
+	c.mu.Unlock()
+
+	if last {
+		_, err = c.svc.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(sess.bucket),
+			Key:             aws.String(sess.key),
+			UploadId:        aws.String(uploadUrl),
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: sess.parts},
+		})
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		sess.completed = true
+		c.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (c *S3Client) GetResumeOffset(ctx context.Context, uploadUrl string) (off int64, complete bool, err error) {
+	sess, ok := c.session(uploadUrl)
+	if !ok {
+		return 0, false, fmt.Errorf("unknown upload session %q", uploadUrl)
+	}
+
+	c.mu.Lock()
+	completed, size := sess.completed, sess.size
+	c.mu.Unlock()
+	if completed {
+		return size, true, nil
+	}
+
+	out, err := c.svc.ListParts(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(sess.bucket),
+		Key:      aws.String(sess.key),
+		UploadId: aws.String(uploadUrl),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, p := range out.Parts {
+		off += aws.ToInt64(p.Size)
+	}
+
+	return off, false, nil
+}
+
+func (c *S3Client) CancelUpload(ctx context.Context, uploadUrl string) (err error) {
+	sess, ok := c.session(uploadUrl)
+	if !ok {
+		return fmt.Errorf("unknown upload session %q", uploadUrl)
+	}
+
+	_, err = c.svc.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(sess.bucket),
+		Key:      aws.String(sess.key),
+		UploadId: aws.String(uploadUrl),
+	})
+
+	c.mu.Lock()
+	delete(c.sessions, uploadUrl)
+	c.mu.Unlock()
+
+	return err
+}
+
+func (c *S3Client) session(uploadUrl string) (sess *s3Session, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sess, ok = c.sessions[uploadUrl]
+	return sess, ok
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+)
+
+// randReader lazily yields size random bytes without buffering them
+// upfront, so the benchmark can push arbitrarily large objects.
+func randReader(size int64) io.Reader {
+	return io.LimitReader(rand.Reader, size)
+}
+
+func saveJson(x any) []byte {
+	b, err := json.Marshal(x)
+	if err != nil {
+		panic("json.Marshal() failed")
+	}
+	return b
+}